@@ -1,6 +1,8 @@
 package frpc
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,7 +10,10 @@ import (
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Call 是一个RPC调用
@@ -142,7 +147,11 @@ func NewClient(conn io.ReadWriteCloser, opt *Option) (*Client, error) {
 		_ = conn.Close()
 		return nil, err
 	}
-	return newClientCodec(f(conn), opt), nil
+	maxSize := opt.MaxResponseSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	return newClientCodec(f(conn, maxSize), opt), nil
 }
 
 // newClientCodec 执行实际上的client创建
@@ -174,15 +183,15 @@ func (c *Client) receive() {
 		case call == nil:
 			// Call不存在
 			err = c.cc.ReadBody(nil)
-		case h.Error != nil:
+		case h.Error != "":
 			// Call存在但出错
-			call.Error = fmt.Errorf("frpc: header error: %v", h.Error)
+			call.Error = fmt.Errorf("frpc: header error: %s", h.Error)
 			err = c.cc.ReadBody(nil)
 			call.done()
 		default:
 			err = c.cc.ReadBody(call.Reply)
 			if err != nil {
-				call.Error = fmt.Errorf("frpc: read body error: %v", h.Error)
+				call.Error = fmt.Errorf("frpc: read body error: %v", err)
 			}
 			call.done()
 		}
@@ -204,29 +213,109 @@ func parseOptions(opts ...*Option) (*Option, error) {
 	if opt.CodecType == "" {
 		opt.CodecType = DefaultOption.CodecType
 	}
+	// 0在这里表示"未设置", 而不是"不限制", 避免一个巨大的声明长度触发无界分配
+	if opt.MaxRequestSize == 0 {
+		opt.MaxRequestSize = DefaultOption.MaxRequestSize
+	}
+	if opt.MaxResponseSize == 0 {
+		opt.MaxResponseSize = DefaultOption.MaxResponseSize
+	}
 	return opt, nil
 }
 
-// Dial 在指定network上建立连接
-func Dial(network, addr string, opts ...*Option) (c *Client, err error) {
-	// 解析配置项
+// clientResult 用于在dialTimeout中通过channel传递NewClient的结果
+type clientResult struct {
+	client *Client
+	err    error
+}
+
+// newClientFunc 是一类根据已建立的连接创建Client的方法, 用于适配裸连接和HTTP CONNECT两种握手方式
+type newClientFunc func(conn io.ReadWriteCloser, opt *Option) (*Client, error)
+
+// dialTimeout 建立连接并在opt.ConnectTimeout内完成f指定的握手, 超时则返回错误
+func dialTimeout(f newClientFunc, network, addr string, opts ...*Option) (c *Client, err error) {
 	opt, err := parseOptions(opts...)
 	if err != nil {
 		return nil, err
 	}
-	// 建立连接
-	conn, err := net.Dial(network, addr)
+	conn, err := net.DialTimeout(network, addr, opt.ConnectTimeout)
 	if err != nil {
 		return nil, err
 	}
-
 	// 创建失败则关闭连接
 	defer func() {
-		if c == nil {
+		if err != nil {
 			_ = conn.Close()
 		}
 	}()
-	return NewClient(conn, opt)
+
+	// ch带缓冲, 否则超时分支返回后没有人再接收, 握手goroutine会永久阻塞在ch<-上而泄漏
+	ch := make(chan clientResult, 1)
+	go func() {
+		client, err := f(conn, opt)
+		ch <- clientResult{client: client, err: err}
+	}()
+
+	if opt.ConnectTimeout == 0 {
+		result := <-ch
+		return result.client, result.err
+	}
+	select {
+	case <-time.After(opt.ConnectTimeout):
+		return nil, fmt.Errorf("rpc client: connect timeout: expect within %s", opt.ConnectTimeout)
+	case result := <-ch:
+		return result.client, result.err
+	}
+}
+
+// Dial 在指定network上建立连接
+func Dial(network, addr string, opts ...*Option) (*Client, error) {
+	return dialTimeout(NewClient, network, addr, opts...)
+}
+
+// newHTTPClient 先通过HTTP CONNECT在指定路径上完成握手, 再将连接移交给NewClient
+// 这是net/rpc的标准部署方式: 借助CONNECT穿透只转发HTTP流量的代理, 与一个http.Server共用端口
+func newHTTPClient(conn io.ReadWriteCloser, path string, opt *Option) (*Client, error) {
+	_, _ = io.WriteString(conn, fmt.Sprintf("CONNECT %s HTTP/1.0\n\n", path))
+
+	// 在切换到RPC协议前, 要求先收到成功的HTTP响应
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && resp.Status == connected {
+		return NewClient(conn, opt)
+	}
+	if err == nil {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	return nil, err
+}
+
+// DialHTTPPath 在指定路径上通过HTTP CONNECT建立连接
+func DialHTTPPath(network, addr, path string, opts ...*Option) (*Client, error) {
+	return dialTimeout(func(conn io.ReadWriteCloser, opt *Option) (*Client, error) {
+		return newHTTPClient(conn, path, opt)
+	}, network, addr, opts...)
+}
+
+// DialHTTP 通过HTTP CONNECT在默认路径DefaultRPCPath上建立连接
+func DialHTTP(network, addr string, opts ...*Option) (*Client, error) {
+	return DialHTTPPath(network, addr, DefaultRPCPath, opts...)
+}
+
+// XDial 根据形如"protocol@address"的rpc地址建立连接
+// protocol为"http"时通过DialHTTP建立连接, 否则将protocol视为network交给Dial
+// 供上层按服务发现返回的地址动态拨号使用, 而不必关心该地址背后用的是哪种传输方式
+func XDial(rpcAddr string, opts ...*Option) (*Client, error) {
+	parts := strings.SplitN(rpcAddr, "@", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rpc client: invalid rpc address %q, expected protocol@addr", rpcAddr)
+	}
+	protocol, addr := parts[0], parts[1]
+	switch protocol {
+	case "http":
+		return DialHTTP("tcp", addr, opts...)
+	default:
+		return Dial(protocol, addr, opts...)
+	}
 }
 
 // send 发送请求
@@ -246,7 +335,7 @@ func (c *Client) send(call *Call) {
 	// 初始化请求头
 	c.header.ServiceMethod = call.ServiceMethod
 	c.header.Seq = seq
-	c.header.Error = nil
+	c.header.Error = ""
 
 	// 写入请求
 	if err := c.cc.Write(&c.header, call.Args); err != nil {
@@ -280,6 +369,21 @@ func (c *Client) Go(serviceMethod string, args interface{}, reply interface{}, d
 
 // Call 同步的执行RPC调用, 并返回错误响应
 func (c *Client) Call(serviceMethod string, args interface{}, reply interface{}) error {
-	call := <-c.Go(serviceMethod, args, reply, make(chan *Call, 1)).Done
-	return call.Error
+	return c.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext 和Call一样同步执行RPC调用, 但ctx被取消或超时时会提前返回, 并将对应的Call从pending中移除
+func (c *Client) CallContext(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+	call := c.Go(serviceMethod, args, reply, make(chan *Call, 1))
+	select {
+	case <-ctx.Done():
+		// removeCall返回nil说明receive已经完成了该Call并写入了Done, 此时不能再次写入
+		if call := c.removeCall(call.Seq); call != nil {
+			call.Error = ctx.Err()
+			call.done()
+		}
+		return ctx.Err()
+	case call := <-call.Done:
+		return call.Error
+	}
 }
@@ -6,7 +6,8 @@ import "io"
 type Header struct {
 	ServiceMethod string // 形如 "Service.Method"
 	Seq           uint64 // 客户端选择的序列号，唯一标识一个请求
-	Error         error  // 错误信息，客户端置为空，发生错误时服务端设置
+	Error         string // 错误信息，客户端置为空，发生错误时服务端设置。与net/rpc的Header一致使用string而非error，
+	// 这样Header才能被JSON等非Gob编码方式直接序列化，调用方通过Call.Error(类型为error)获取真正的错误值
 }
 
 // Codec 是抽象出的编解码器接口，用于适配不同的编解码方式
@@ -18,15 +19,16 @@ type Codec interface {
 }
 
 // NewCodecFunc 是一类创建Codec的方法，用于实现工厂方法
-type NewCodecFunc func(io.ReadWriteCloser) Codec
+// maxFrameSize 限制了该编解码器读取的单个帧(Header或Body)的最大字节数，0表示不限制
+type NewCodecFunc func(conn io.ReadWriteCloser, maxFrameSize uint32) Codec
 
 // Type 是自定义的类型，用于区分不同的编码方式
 type Type string
 
-// 定义编码方式常量，暂时只实现了gob
+// 定义编码方式常量
 const (
 	GobType  = "application/gob"  // Gob是Go语言特有的二进制序列化格式，专为高效传输Go数据类型设计
-	JsonType = "application/json" // 代码里目前没有实现
+	JsonType = "application/json" // JSON是跨语言的文本序列化格式
 )
 
 var NewCodecFuncMap map[Type]NewCodecFunc
@@ -35,4 +37,5 @@ var NewCodecFuncMap map[Type]NewCodecFunc
 func init() {
 	NewCodecFuncMap = make(map[Type]NewCodecFunc)
 	NewCodecFuncMap[GobType] = NewGobCodec
+	NewCodecFuncMap[JsonType] = NewJsonCodec
 }
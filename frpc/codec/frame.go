@@ -0,0 +1,41 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFrameTooLarge 在一帧声明的长度超过调用方配置的上限时返回
+var ErrFrameTooLarge = errors.New("codec: frame exceeds configured size limit")
+
+// WriteFrame 以uint32长度前缀加载荷的形式写入一帧
+// 导出供server.go在协商失败(codec尚未创建)时复用同一种帧格式
+func WriteFrame(w io.Writer, payload []byte) error {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(payload)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame 读取一帧uint32长度前缀加载荷。maxSize为0表示不限制，否则在读取载荷前校验长度，
+// 避免恶意或损坏的对端通过声明一个巨大的长度制造无界内存分配
+func readFrame(r io.Reader, maxSize uint32) ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(lenBuf[:])
+	if maxSize > 0 && size > maxSize {
+		return nil, fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, size, maxSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
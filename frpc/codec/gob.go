@@ -2,47 +2,57 @@ package codec
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/gob"
 	"io"
 	"log"
 )
 
 // GobCodec 是Gob类型的编解码器
+// Header和Body各自作为一个独立的、长度前缀的帧进行编解码，详见frame.go
 type GobCodec struct {
 	// 通过TCP或Unix建立的连接实例
 	conn io.ReadWriteCloser
 	// 防止阻塞的writer，用于提升性能
 	buf *bufio.Writer
-	// gob的编码和解码器
-	dec *gob.Decoder
-	enc *gob.Encoder
+	// maxFrameSize 限制了单个帧的最大字节数，0表示不限制
+	maxFrameSize uint32
 }
 
 // 校验是否实现Codec接口
 var _ Codec = (*GobCodec)(nil)
 
-// NewGobCodec 根据conn构造一个GocCodec
-func NewGobCodec(conn io.ReadWriteCloser) Codec {
-	buf := bufio.NewWriter(conn)
+// NewGobCodec 根据conn构造一个GobCodec
+func NewGobCodec(conn io.ReadWriteCloser, maxFrameSize uint32) Codec {
 	return &GobCodec{
-		conn: conn,
-		buf:  buf,
-		dec:  gob.NewDecoder(conn),
-		enc:  gob.NewEncoder(conn),
+		conn:         conn,
+		buf:          bufio.NewWriter(conn),
+		maxFrameSize: maxFrameSize,
 	}
 }
 
-// ReadHeader 从buf中读取数据并存入header
+// ReadHeader 读取一帧并将其解码为header
 func (c *GobCodec) ReadHeader(h *Header) error {
-	return c.dec.Decode(h)
+	payload, err := readFrame(c.conn, c.maxFrameSize)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(payload)).Decode(h)
 }
 
-// ReadBody 从buf中读取数据并存入body
+// ReadBody 读取一帧并将其解码为body，body为nil时只消费该帧而不解码，用于跳过出错的响应体
 func (c *GobCodec) ReadBody(body interface{}) error {
-	return c.dec.Decode(body)
+	payload, err := readFrame(c.conn, c.maxFrameSize)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return nil
+	}
+	return gob.NewDecoder(bytes.NewReader(payload)).Decode(body)
 }
 
-// Write 写入Header和Body，并在结束时刷新缓冲区
+// Write 将Header和Body分别编码为独立的帧写入，并在结束时刷新缓冲区
 func (c *GobCodec) Write(h *Header, body interface{}) (err error) {
 	defer func() {
 		_ = c.buf.Flush()
@@ -50,15 +60,20 @@ func (c *GobCodec) Write(h *Header, body interface{}) (err error) {
 			_ = c.conn.Close()
 		}
 	}()
-	if err := c.enc.Encode(h); err != nil {
+	var headerBuf bytes.Buffer
+	if err = gob.NewEncoder(&headerBuf).Encode(h); err != nil {
 		log.Println("rpc codec: gob error encoding header:", err)
 		return err
 	}
-	if err := c.enc.Encode(body); err != nil {
+	if err = WriteFrame(c.buf, headerBuf.Bytes()); err != nil {
+		return err
+	}
+	var bodyBuf bytes.Buffer
+	if err = gob.NewEncoder(&bodyBuf).Encode(body); err != nil {
 		log.Println("rpc codec: gob error encoding body:", err)
 		return err
 	}
-	return nil
+	return WriteFrame(c.buf, bodyBuf.Bytes())
 }
 
 // Close 关闭连接
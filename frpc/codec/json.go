@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+)
+
+// JsonCodec 是Json类型的编解码器
+// Header和Body各自作为一个独立的、长度前缀的帧进行编解码，详见frame.go
+type JsonCodec struct {
+	// 通过TCP或Unix建立的连接实例
+	conn io.ReadWriteCloser
+	// 防止阻塞的writer，用于提升性能
+	buf *bufio.Writer
+	// maxFrameSize 限制了单个帧的最大字节数，0表示不限制
+	maxFrameSize uint32
+}
+
+// 校验是否实现Codec接口
+var _ Codec = (*JsonCodec)(nil)
+
+// NewJsonCodec 根据conn构造一个JsonCodec
+func NewJsonCodec(conn io.ReadWriteCloser, maxFrameSize uint32) Codec {
+	return &JsonCodec{
+		conn:         conn,
+		buf:          bufio.NewWriter(conn),
+		maxFrameSize: maxFrameSize,
+	}
+}
+
+// ReadHeader 读取一帧并将其解码为header
+func (c *JsonCodec) ReadHeader(h *Header) error {
+	payload, err := readFrame(c.conn, c.maxFrameSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, h)
+}
+
+// ReadBody 读取一帧并将其解码为body，body为nil时只消费该帧而不解码，用于跳过出错的响应体
+func (c *JsonCodec) ReadBody(body interface{}) error {
+	payload, err := readFrame(c.conn, c.maxFrameSize)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return nil
+	}
+	return json.Unmarshal(payload, body)
+}
+
+// Write 将Header和Body分别编码为独立的帧写入，并在结束时刷新缓冲区
+func (c *JsonCodec) Write(h *Header, body interface{}) (err error) {
+	defer func() {
+		_ = c.buf.Flush()
+		if err != nil {
+			_ = c.conn.Close()
+		}
+	}()
+	headerBytes, err := json.Marshal(h)
+	if err != nil {
+		log.Println("rpc codec: json error encoding header:", err)
+		return err
+	}
+	if err = WriteFrame(c.buf, headerBytes); err != nil {
+		return err
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		log.Println("rpc codec: json error encoding body:", err)
+		return err
+	}
+	return WriteFrame(c.buf, bodyBytes)
+}
+
+// Close 关闭连接
+func (c *JsonCodec) Close() error {
+	return c.conn.Close()
+}
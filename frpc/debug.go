@@ -0,0 +1,55 @@
+package frpc
+
+// DebugServiceName 是内置调试服务注册时使用的服务名
+const DebugServiceName = "_frpc"
+
+// MethodInfo 描述一个已注册方法的元信息
+type MethodInfo struct {
+	Name      string
+	ArgType   string
+	ReplyType string
+	NumCalls  uint64
+}
+
+// ServiceInfo 描述一个已注册服务及其方法的元信息
+type ServiceInfo struct {
+	Name    string
+	Methods []MethodInfo
+}
+
+// ServiceList 返回当前已注册的所有服务及其方法的元信息, 包含已调用的次数
+func (s *Server) ServiceList() []ServiceInfo {
+	var list []ServiceInfo
+	s.serviceMap.Range(func(_, value interface{}) bool {
+		serv := value.(*service)
+		info := ServiceInfo{Name: serv.name}
+		for name, m := range serv.method {
+			info.Methods = append(info.Methods, MethodInfo{
+				Name:      name,
+				ArgType:   m.ArgType.String(),
+				ReplyType: m.ReplyType.String(),
+				NumCalls:  m.NumCalls(),
+			})
+		}
+		list = append(list, info)
+		return true
+	})
+	return list
+}
+
+// ServiceList 使用默认server返回已注册的所有服务及其方法的元信息
+func ServiceList() []ServiceInfo {
+	return DefaultServer.ServiceList()
+}
+
+// DebugService 是内置的调试服务, 以DebugServiceName注册, 使操作者可以像调用普通RPC方法一样
+// 查询一个运行中的Server暴露了哪些服务、方法以及各自的调用次数, 而无需附加调试器
+type DebugService struct {
+	server *Server
+}
+
+// Debug 返回server当前注册的所有服务、方法及各自的调用次数
+func (d *DebugService) Debug(_ struct{}, reply *[]ServiceInfo) error {
+	*reply = d.server.ServiceList()
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package loadBalancer 在frpc之上提供服务发现与负载均衡, 使frpc能用于有多个对等服务实例的部署场景
+package loadBalancer
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SelectMode 决定XClient在多个服务地址之间如何选择
+type SelectMode int
+
+const (
+	// RandomSelect 随机选择一个服务地址
+	RandomSelect SelectMode = iota
+	// RoundRobinSelect 轮询选择服务地址
+	RoundRobinSelect
+)
+
+// Discovery 是服务发现的抽象接口
+type Discovery interface {
+	// Get 根据mode选择一个服务地址
+	Get(mode SelectMode) (string, error)
+	// GetAll 返回当前已知的全部服务地址
+	GetAll() ([]string, error)
+	// Refresh 从数据源刷新服务地址列表
+	Refresh()
+	// Update 手动更新服务地址列表
+	Update(servers []string)
+}
+
+// MultiServersDiscovery 是一个手动维护服务地址列表的Discovery实现
+type MultiServersDiscovery struct {
+	r       *rand.Rand
+	mu      sync.Mutex
+	servers []string
+	index   int
+}
+
+var _ Discovery = (*MultiServersDiscovery)(nil)
+
+// NewMultiServersDiscovery 根据给定的服务地址列表构造一个MultiServersDiscovery
+func NewMultiServersDiscovery(servers []string) *MultiServersDiscovery {
+	d := &MultiServersDiscovery{
+		servers: servers,
+		r:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	// 轮询的起始位置随机, 避免多个客户端总是从同一个服务开始
+	d.index = d.r.Intn(math.MaxInt32 - 1)
+	return d
+}
+
+// Refresh 对MultiServersDiscovery是空操作, 服务列表只能通过Update手动更新
+func (d *MultiServersDiscovery) Refresh() {}
+
+// Update 替换当前的服务地址列表
+func (d *MultiServersDiscovery) Update(servers []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.servers = servers
+}
+
+// Get 根据mode选择一个服务地址
+func (d *MultiServersDiscovery) Get(mode SelectMode) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.servers)
+	if n == 0 {
+		return "", errors.New("loadBalancer: no available servers")
+	}
+	switch mode {
+	case RandomSelect:
+		return d.servers[d.r.Intn(n)], nil
+	case RoundRobinSelect:
+		s := d.servers[d.index%n]
+		d.index = (d.index + 1) % n
+		return s, nil
+	default:
+		return "", errors.New("loadBalancer: not supported select mode")
+	}
+}
+
+// GetAll 返回当前服务地址列表的一份拷贝
+func (d *MultiServersDiscovery) GetAll() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	servers := make([]string, len(d.servers))
+	copy(servers, d.servers)
+	return servers, nil
+}
@@ -0,0 +1,81 @@
+package loadBalancer
+
+import (
+	"github.com/univero/frpc/registry"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultRefreshInterval 是未指定刷新间隔时, 两次轮询registry之间的最短间隔
+const defaultRefreshInterval = time.Second * 10
+
+// RegistryDiscovery 是一个从轻量HTTP注册中心周期性拉取存活服务列表的Discovery实现
+type RegistryDiscovery struct {
+	*MultiServersDiscovery
+	registry        string
+	refreshInterval time.Duration
+	lastRefresh     time.Time
+}
+
+var _ Discovery = (*RegistryDiscovery)(nil)
+
+// NewRegistryDiscovery 根据registry地址构造一个RegistryDiscovery, refreshInterval为0时使用默认值
+func NewRegistryDiscovery(registryAddr string, refreshInterval time.Duration) *RegistryDiscovery {
+	if refreshInterval == 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	return &RegistryDiscovery{
+		MultiServersDiscovery: NewMultiServersDiscovery(make([]string, 0)),
+		registry:              registryAddr,
+		refreshInterval:       refreshInterval,
+	}
+}
+
+// Update 手动更新服务地址列表, 同时重置刷新计时, 避免Get/GetAll立即触发一次多余的Refresh
+func (d *RegistryDiscovery) Update(servers []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.servers = servers
+	d.lastRefresh = time.Now()
+}
+
+// Refresh 在距上次刷新超过refreshInterval时, 从registry重新拉取存活服务列表
+func (d *RegistryDiscovery) Refresh() {
+	d.mu.Lock()
+	if d.lastRefresh.Add(d.refreshInterval).After(time.Now()) {
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+
+	resp, err := http.Get(d.registry)
+	if err != nil {
+		log.Println("loadBalancer: refresh servers from registry failed:", err)
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	servers := strings.Split(resp.Header.Get(registry.HeaderServers), ",")
+	fresh := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if s = strings.TrimSpace(s); s != "" {
+			fresh = append(fresh, s)
+		}
+	}
+	d.Update(fresh)
+}
+
+// Get 在选择服务地址前先尝试刷新列表
+func (d *RegistryDiscovery) Get(mode SelectMode) (string, error) {
+	d.Refresh()
+	return d.MultiServersDiscovery.Get(mode)
+}
+
+// GetAll 在返回全部地址前先尝试刷新列表
+func (d *RegistryDiscovery) GetAll() ([]string, error) {
+	d.Refresh()
+	return d.MultiServersDiscovery.GetAll()
+}
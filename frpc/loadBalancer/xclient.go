@@ -0,0 +1,131 @@
+package loadBalancer
+
+import (
+	"context"
+	"github.com/univero/frpc"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// XClient 是一个基于Discovery做负载均衡的frpc客户端封装
+// 它按需拨号并缓存与每个服务地址的连接, 在Discovery发现的地址之间做Call或Broadcast
+type XClient struct {
+	d       Discovery
+	mode    SelectMode
+	opt     *frpc.Option
+	mu      sync.Mutex
+	clients map[string]*frpc.Client
+}
+
+var _ io.Closer = (*XClient)(nil)
+
+// NewXClient 构造一个新的XClient
+func NewXClient(d Discovery, mode SelectMode, opt *frpc.Option) *XClient {
+	return &XClient{
+		d:       d,
+		mode:    mode,
+		opt:     opt,
+		clients: make(map[string]*frpc.Client),
+	}
+}
+
+// Close 关闭所有已缓存的连接
+func (xc *XClient) Close() error {
+	xc.mu.Lock()
+	defer xc.mu.Unlock()
+	for key, client := range xc.clients {
+		_ = client.Close()
+		delete(xc.clients, key)
+	}
+	return nil
+}
+
+// dial 返回与rpcAddr对应的Client, 必要时建立新连接并缓存
+func (xc *XClient) dial(rpcAddr string) (*frpc.Client, error) {
+	xc.mu.Lock()
+	defer xc.mu.Unlock()
+	client, ok := xc.clients[rpcAddr]
+	if ok && !client.IsAvailable() {
+		_ = client.Close()
+		delete(xc.clients, rpcAddr)
+		client = nil
+	}
+	if client == nil {
+		var err error
+		client, err = frpc.XDial(rpcAddr, xc.opt)
+		if err != nil {
+			return nil, err
+		}
+		xc.clients[rpcAddr] = client
+	}
+	return client, nil
+}
+
+// call 向rpcAddr指定的服务发起一次调用
+func (xc *XClient) call(ctx context.Context, rpcAddr, serviceMethod string, args, reply interface{}) error {
+	client, err := xc.dial(rpcAddr)
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, serviceMethod, args, reply)
+}
+
+// Call 通过Discovery按配置的SelectMode选择一个服务地址, 并发起一次调用
+func (xc *XClient) Call(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+	rpcAddr, err := xc.d.Get(xc.mode)
+	if err != nil {
+		return err
+	}
+	return xc.call(ctx, rpcAddr, serviceMethod, args, reply)
+}
+
+// Broadcast 向Discovery返回的全部服务地址发起调用, 返回首个成功的结果
+// 一旦有一个地址调用成功, 会取消尚未完成的调用; reply只会被首个成功的响应填充一次
+func (xc *XClient) Broadcast(ctx context.Context, serviceMethod string, args, reply interface{}) error {
+	servers, err := xc.d.GetAll()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var succeeded bool
+	replyDone := reply == nil
+
+	for _, rpcAddr := range servers {
+		wg.Add(1)
+		go func(rpcAddr string) {
+			defer wg.Done()
+			var clonedReply interface{}
+			if reply != nil {
+				clonedReply = reflect.New(reflect.ValueOf(reply).Elem().Type()).Interface()
+			}
+			err := xc.call(ctx, rpcAddr, serviceMethod, args, clonedReply)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			// 已经有一次成功的调用, 其余尚未完成的调用不再需要继续
+			succeeded = true
+			cancel()
+			if !replyDone {
+				reflect.ValueOf(reply).Elem().Set(reflect.ValueOf(clonedReply).Elem())
+				replyDone = true
+			}
+		}(rpcAddr)
+	}
+	wg.Wait()
+	if succeeded {
+		return nil
+	}
+	return firstErr
+}
@@ -0,0 +1,158 @@
+// Package registry 实现了一个轻量的、基于HTTP的frpc服务注册中心
+// 服务端周期性地通过PUT请求上报心跳及TTL, 客户端通过GET请求获取当前存活的服务地址列表
+package registry
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPath 是Registry默认注册的HTTP路径
+	DefaultPath = "/_frpc_/registry"
+	// defaultTTL 是心跳未携带TTL时使用的默认存活时长
+	defaultTTL = time.Minute * 5
+
+	// HeaderServer 携带上报心跳的服务地址
+	HeaderServer = "X-Frpc-Server"
+	// HeaderTTL 携带本次心跳的存活时长, 形如time.Duration.String()的格式
+	HeaderTTL = "X-Frpc-Ttl"
+	// HeaderServers 携带以逗号分隔的存活服务地址列表
+	HeaderServers = "X-Frpc-Servers"
+)
+
+// serverItem 记录一个服务地址最近一次心跳的时间及其TTL
+type serverItem struct {
+	addr  string
+	ttl   time.Duration
+	start time.Time
+}
+
+// Registry 是一个基于HTTP的注册中心, 维护心跳上报的服务地址及其存活状态
+type Registry struct {
+	mu      sync.Mutex
+	servers map[string]*serverItem
+}
+
+// New 构造一个新的Registry
+func New() *Registry {
+	return &Registry{servers: make(map[string]*serverItem)}
+}
+
+// DefaultRegistry 是默认的Registry实例
+var DefaultRegistry = New()
+
+// putServer 记录一次心跳, ttl<=0时使用defaultTTL
+func (r *Registry) putServer(addr string, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	s := r.servers[addr]
+	if s == nil {
+		r.servers[addr] = &serverItem{addr: addr, ttl: ttl, start: time.Now()}
+		return
+	}
+	s.ttl = ttl
+	s.start = time.Now()
+}
+
+// aliveServers 返回未过期的服务地址, 并清理已过期的记录
+func (r *Registry) aliveServers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var alive []string
+	for addr, s := range r.servers {
+		if s.start.Add(s.ttl).After(time.Now()) {
+			alive = append(alive, addr)
+		} else {
+			delete(r.servers, addr)
+		}
+	}
+	sort.Strings(alive)
+	return alive
+}
+
+// ServeHTTP 实现了http.Handler
+// GET 通过HeaderServers返回当前存活的服务地址列表
+// PUT 接受一次心跳上报, 地址由HeaderServer给出, TTL由HeaderTTL给出(可选)
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		w.Header().Set(HeaderServers, strings.Join(r.aliveServers(), ","))
+	case http.MethodPut:
+		addr := req.Header.Get(HeaderServer)
+		if addr == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if raw := req.Header.Get(HeaderTTL); raw != "" {
+			if parsed, err := time.ParseDuration(raw); err == nil {
+				ttl = parsed
+			}
+		}
+		r.putServer(addr, ttl)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleHTTP 在指定路径上注册Registry的HTTP处理器
+func (r *Registry) HandleHTTP(path string) {
+	http.Handle(path, r)
+}
+
+// HandleHTTP 使用DefaultRegistry在DefaultPath上注册HTTP处理器
+func HandleHTTP() {
+	DefaultRegistry.HandleHTTP(DefaultPath)
+}
+
+// Heartbeat 周期性地向registry发送心跳, 直到某次心跳出错为止
+// interval为0时默认取ttl的2/3, 以确保在过期前完成续约
+func Heartbeat(registry, addr string, ttl, interval time.Duration) {
+	if interval == 0 {
+		if ttl > 0 {
+			interval = ttl * 2 / 3
+		} else {
+			interval = defaultTTL * 2 / 3
+		}
+	}
+	var err error
+	err = sendHeartbeat(registry, addr, ttl)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for err == nil {
+			<-t.C
+			err = sendHeartbeat(registry, addr, ttl)
+		}
+	}()
+}
+
+// sendHeartbeat 向registry发送一次心跳
+func sendHeartbeat(registry, addr string, ttl time.Duration) error {
+	log.Println(addr, "send heartbeat to registry", registry)
+	req, err := http.NewRequest(http.MethodPut, registry, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderServer, addr)
+	if ttl > 0 {
+		req.Header.Set(HeaderTTL, ttl.String())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Println("rpc registry: heartbeat err:", err)
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return nil
+}
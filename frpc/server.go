@@ -1,32 +1,58 @@
 package frpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/univero/frpc/codec"
 	"io"
 	"log"
 	"net"
+	"net/http"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // MagicNumber 是标识协议的"魔法数字", 这里选用的是FRPC的ASCII码表示
 const MagicNumber = 0x46525043
 
+// connected 是CONNECT握手成功后返回给客户端的状态行
+const connected = "200 Connected to FRPC"
+
+// DefaultRPCPath 是HandleHTTP默认注册的路径
+const DefaultRPCPath = "/_frpc_"
+
+// DefaultMaxFrameSize 是MaxRequestSize/MaxResponseSize为0(未设置)时实际生效的帧大小上限
+// 0表示"无限制"会让攻击者用一个声明了巨大长度的4字节前缀触发无界内存分配,
+// 因此0在解析配置时会被当作"使用这个有界默认值"而不是真正的不限制
+const DefaultMaxFrameSize = 4 << 20 // 4MiB
+
 // Option 配置项, 用于协商各类信息
 type Option struct {
 	// 表示frpc协议的magic number
 	MagicNumber int
 	// 使用的编解码类型
 	CodecType codec.Type
+	// ConnectTimeout 建立连接及完成Option握手的超时时间, 0表示不限制
+	ConnectTimeout time.Duration
+	// HandleTimeout 服务端处理一次请求的超时时间, 0表示不限制
+	HandleTimeout time.Duration
+	// MaxRequestSize 服务端读取单个请求(Header或Body)的帧的最大字节数, 0表示使用DefaultMaxFrameSize
+	MaxRequestSize uint32
+	// MaxResponseSize 客户端读取单个响应(Header或Body)的帧的最大字节数, 0表示使用DefaultMaxFrameSize
+	MaxResponseSize uint32
 }
 
 // DefaultOption 默认配置项
 var DefaultOption = &Option{
-	MagicNumber: MagicNumber,
-	CodecType:   codec.GobType,
+	MagicNumber:     MagicNumber,
+	CodecType:       codec.GobType,
+	ConnectTimeout:  time.Second * 10,
+	MaxRequestSize:  DefaultMaxFrameSize,
+	MaxResponseSize: DefaultMaxFrameSize,
 }
 
 // Server 是一个FRPC服务端
@@ -34,9 +60,11 @@ type Server struct {
 	serviceMap sync.Map
 }
 
-// NewServer 构造一个新的服务端
+// NewServer 构造一个新的服务端, 并附带注册内置的调试服务(参见DebugService)
 func NewServer() *Server {
-	return &Server{}
+	s := &Server{}
+	_ = s.RegisterName(DebugServiceName, &DebugService{server: s})
+	return s
 }
 
 // DefaultServer 是默认的FRPC服务端实例
@@ -76,20 +104,36 @@ func (s *Server) ServerConn(conn net.Conn) {
 		log.Println("rpc server: invalid magic number", opt.MagicNumber)
 		return
 	}
+	// opt来自尚未信任的对端, 0既可能是显式设置也可能只是零值, 两种情况都不能当作"不限制"
+	if opt.MaxRequestSize == 0 {
+		opt.MaxRequestSize = DefaultMaxFrameSize
+	}
 	// 获取编码类型的构造函数
 	f := codec.NewCodecFuncMap[opt.CodecType]
 	if f == nil {
-		log.Println("rpc server: invalid codec type", opt.CodecType)
+		err := fmt.Errorf("rpc server: invalid codec type %s", opt.CodecType)
+		log.Println(err)
+		// 以和其他报文一致的长度前缀帧格式告知客户端协商失败, 而不是直接断开连接;
+		// codec尚未创建, 因此直接复用codec包导出的帧写入方式
+		data, merr := json.Marshal(negotiationError{Error: err.Error()})
+		if merr == nil {
+			_ = codec.WriteFrame(conn, data)
+		}
 		return
 	}
 	// 处理编解码器
-	s.serverCodec(f(conn))
+	s.serverCodec(f(conn, opt.MaxRequestSize), &opt)
+}
+
+// negotiationError 用于在协商失败时(如不支持的CodecType)返回给客户端的结构化错误
+type negotiationError struct {
+	Error string
 }
 
 var invalidRequest = struct{}{}
 
 // serverCodec 处理一个编解码器
-func (s *Server) serverCodec(cc codec.Codec) {
+func (s *Server) serverCodec(cc codec.Codec, opt *Option) {
 	sending := new(sync.Mutex)
 	defer sending.Unlock()
 	wg := new(sync.WaitGroup) // wait until all request are handled
@@ -101,12 +145,12 @@ func (s *Server) serverCodec(cc codec.Codec) {
 			if req == nil {
 				break // it's not possible to recover, so close the connection
 			}
-			req.h.Error = err
+			req.h.Error = err.Error()
 			s.sendResponse(cc, req.h, invalidRequest, sending)
 			continue
 		}
 		wg.Add(1)
-		go s.handleRequest(cc, req, sending, wg)
+		go s.handleRequest(cc, req, sending, wg, opt.HandleTimeout)
 	}
 
 	// 等待所有请求完成，关闭连接
@@ -146,6 +190,9 @@ func (s *Server) readRequest(cc codec.Codec) (*request, error) {
 	// 读取body
 	req.serv, req.mType, err = s.findService(h.ServiceMethod)
 	if err != nil {
+		// body帧仍然留在连接上, 必须消费掉(但不解码), 否则下一次ReadHeader会把它错读成header,
+		// 使整条连接的帧边界错位
+		_ = cc.ReadBody(nil)
 		return req, err
 	}
 	req.argv = req.mType.newArgv()
@@ -172,13 +219,24 @@ func (s *Server) sendResponse(cc codec.Codec, h *codec.Header, body interface{},
 	}
 }
 
-// handleRequest 处理一个请求
-func (s *Server) handleRequest(cc codec.Codec, req *request, sending *sync.Mutex, wg *sync.WaitGroup) {
+// handleRequest 处理一个请求, timeout为0表示不限制处理时长
+// 超过timeout仍未返回时, 会提前发送超时错误响应, 但处理handler的goroutine仍会在后台跑完
+func (s *Server) handleRequest(cc codec.Codec, req *request, sending *sync.Mutex, wg *sync.WaitGroup, timeout time.Duration) {
 	defer wg.Done()
-	err := req.serv.call(req.mType, req.argv, req.replyv)
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := req.serv.callContext(ctx, req.mType, req.argv, req.replyv)
 	if err != nil {
-		req.h.Error = err
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("rpc server: request handle timeout: expect within %s", timeout)
+		}
+		req.h.Error = err.Error()
 		s.sendResponse(cc, req.h, invalidRequest, sending)
+		return
 	}
 	s.sendResponse(cc, req.h, req.replyv.Interface(), sending)
 }
@@ -197,6 +255,54 @@ func Register(rcvr interface{}) error {
 	return DefaultServer.Register(rcvr)
 }
 
+// RegisterName 类似Register, 但使用name作为服务名, 而不是rcvr反射得到的类型名
+func (s *Server) RegisterName(name string, rcvr interface{}) error {
+	serv := newService(rcvr)
+	serv.name = name
+	if _, dip := s.serviceMap.LoadOrStore(serv.name, serv); dip {
+		return errors.New("rpc server: service already defined, " + serv.name)
+	}
+	return nil
+}
+
+// RegisterName 使用默认server, 以指定name注册
+func RegisterName(name string, rcvr interface{}) error {
+	return DefaultServer.RegisterName(name, rcvr)
+}
+
+// ServeHTTP 实现了http.Handler, 用于响应CONNECT请求并劫持连接交由ServerConn处理
+// 这使得frpc可以和一个普通的http.Server共用端口, 并能穿透只转发HTTP流量的代理
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		log.Println("rpc hijacking", req.RemoteAddr, ":", err.Error())
+		return
+	}
+	_, _ = io.WriteString(conn, "HTTP/1.0 "+connected+"\n\n")
+	s.ServerConn(conn)
+}
+
+// HandleHTTPPath 在指定路径上注册Server的HTTP处理器, 用于接受CONNECT请求
+func (s *Server) HandleHTTPPath(path string) {
+	http.Handle(path, s)
+}
+
+// HandleHTTP 在默认路径DefaultRPCPath上注册Server的HTTP处理器
+func (s *Server) HandleHTTP() {
+	s.HandleHTTPPath(DefaultRPCPath)
+}
+
+// HandleHTTP 使用默认Server在默认路径上注册HTTP处理器
+func HandleHTTP() {
+	DefaultServer.HandleHTTP()
+}
+
 // 根据<service>.<method>找到service中对应的method
 func (s *Server) findService(serviceMethod string) (serv *service, mType *methodType, err error) {
 	dot := strings.LastIndex(serviceMethod, ".")
@@ -208,6 +314,7 @@ func (s *Server) findService(serviceMethod string) (serv *service, mType *method
 	servi, ok := s.serviceMap.Load(servName)
 	if !ok {
 		err = errors.New("rpc server: can't find service: " + serviceMethod)
+		return
 	}
 	serv = servi.(*service)
 	mType = serv.method[methodName]
@@ -1,6 +1,7 @@
 package frpc
 
 import (
+	"context"
 	"go/ast"
 	"log"
 	"reflect"
@@ -114,3 +115,18 @@ func (s *service) call(m *methodType, argv, replyv reflect.Value) error {
 	}
 	return nil
 }
+
+// callContext 和call一样执行反射调用, 但ctx被取消或超时时会提前返回ctx.Err()
+// 提前返回后, 底层的call仍会在后台的goroutine中跑完, 不会被中断
+func (s *service) callContext(ctx context.Context, m *methodType, argv, replyv reflect.Value) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.call(m, argv, replyv)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}